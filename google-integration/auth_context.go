@@ -0,0 +1,81 @@
+package googleintegration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	oauth2v2 "google.golang.org/api/oauth2/v2"
+)
+
+// authContextKey is an unexported type so values stored under it can't
+// collide with context keys set by other packages (the untyped string key
+// "auth" used previously was flagged by go vet for exactly this reason).
+type authContextKey struct{}
+
+// loggingFieldsKey is the context key under which per-request structured
+// logging fields are stored.
+type loggingFieldsKey struct{}
+
+// AuthContext carries everything AuthMiddleware resolved about the caller
+// so downstream handlers don't need to re-parse headers or re-validate the
+// token.
+type AuthContext struct {
+	TokenInfo    *oauth2v2.Tokeninfo
+	Permissions  []string
+	ProjectID    string
+	ServiceEmail string
+	RawToken     string
+}
+
+// WithAuthContext returns a copy of ctx carrying ac, retrievable via
+// AuthContextFromRequest (or AuthContextFromContext for non-HTTP callers).
+func WithAuthContext(ctx context.Context, ac *AuthContext) context.Context {
+	return context.WithValue(ctx, authContextKey{}, ac)
+}
+
+// AuthContextFromContext retrieves the AuthContext stashed by AuthMiddleware.
+func AuthContextFromContext(ctx context.Context) (*AuthContext, bool) {
+	ac, ok := ctx.Value(authContextKey{}).(*AuthContext)
+	return ac, ok
+}
+
+// AuthContextFromRequest retrieves the AuthContext stashed by AuthMiddleware
+// on the request's context. Downstream handlers in adjacent packages
+// should use this instead of reading the untyped "auth" context key.
+func AuthContextFromRequest(r *http.Request) (*AuthContext, bool) {
+	return AuthContextFromContext(r.Context())
+}
+
+// LoggingFields are per-request fields threaded through context.Context so
+// logs emitted anywhere in the call chain can be correlated by request_id.
+type LoggingFields struct {
+	RequestID  string
+	UserEmail  string
+	Repository string
+	Action     string
+}
+
+// WithLoggingFields returns a copy of ctx carrying fields.
+func WithLoggingFields(ctx context.Context, fields LoggingFields) context.Context {
+	return context.WithValue(ctx, loggingFieldsKey{}, fields)
+}
+
+// LoggingFieldsFromContext retrieves the LoggingFields stashed by
+// AuthMiddleware, returning the zero value if none were set.
+func LoggingFieldsFromContext(ctx context.Context) LoggingFields {
+	fields, _ := ctx.Value(loggingFieldsKey{}).(LoggingFields)
+	return fields
+}
+
+// String renders the fields as "key=value" pairs for inclusion in a
+// log.Printf call until the bridge adopts a structured logging library.
+func (f LoggingFields) String() string {
+	return fmt.Sprintf("request_id=%s user_email=%s repository=%s action=%s",
+		f.RequestID, f.UserEmail, f.Repository, f.Action)
+}
+
+func generateAuthRequestID() string {
+	return fmt.Sprintf("authreq_%d", time.Now().UnixNano())
+}
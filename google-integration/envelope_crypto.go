@@ -0,0 +1,133 @@
+package googleintegration
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	kms "google.golang.org/api/cloudkms/v1"
+)
+
+// envelopeCipher encrypts CachedTokens at rest before they're written to an
+// external TokenStore (Redis, Firestore), using envelope encryption: a
+// fresh AES-256 data-encryption key (DEK) is generated per value, used to
+// seal the token with AES-GCM, and itself wrapped by a Cloud KMS key so the
+// raw DEK never touches the store.
+type envelopeCipher struct {
+	kmsService *kms.Service
+	keyName    string // e.g. "projects/p/locations/global/keyRings/bridge/cryptoKeys/token-cache"
+	ctx        context.Context
+}
+
+// newEnvelopeCipher creates an envelopeCipher backed by the given KMS
+// CryptoKey resource name.
+func newEnvelopeCipher(ctx context.Context, kmsService *kms.Service, keyName string) *envelopeCipher {
+	return &envelopeCipher{kmsService: kmsService, keyName: keyName, ctx: ctx}
+}
+
+// sealedToken is the on-the-wire representation written to external
+// stores: a KMS-wrapped DEK plus the AES-GCM-sealed token bytes.
+type sealedToken struct {
+	WrappedDEK []byte `json:"wrapped_dek"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// encryptToken generates a fresh DEK, wraps it via KMS, and seals token
+// with it, returning a single serialized blob suitable for storage.
+func (e *envelopeCipher) encryptToken(token *CachedToken) ([]byte, error) {
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal token: %w", err)
+	}
+
+	dek := make([]byte, 32) // AES-256
+	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
+		return nil, fmt.Errorf("failed to generate DEK: %w", err)
+	}
+
+	block, err := aes.NewCipher(dek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	ciphertext := gcm.Seal(nil, nonce, plaintext, nil)
+
+	wrappedDEK, err := e.wrapDEK(dek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap DEK via KMS: %w", err)
+	}
+
+	return json.Marshal(sealedToken{
+		WrappedDEK: wrappedDEK,
+		Nonce:      nonce,
+		Ciphertext: ciphertext,
+	})
+}
+
+// decryptToken reverses encryptToken: it unwraps the DEK via KMS and opens
+// the AES-GCM-sealed token.
+func (e *envelopeCipher) decryptToken(blob []byte) (*CachedToken, error) {
+	var sealed sealedToken
+	if err := json.Unmarshal(blob, &sealed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal sealed token: %w", err)
+	}
+
+	dek, err := e.unwrapDEK(sealed.WrappedDEK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap DEK via KMS: %w", err)
+	}
+
+	block, err := aes.NewCipher(dek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, sealed.Nonce, sealed.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt token: %w", err)
+	}
+
+	var token CachedToken
+	if err := json.Unmarshal(plaintext, &token); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal decrypted token: %w", err)
+	}
+
+	return &token, nil
+}
+
+func (e *envelopeCipher) wrapDEK(dek []byte) ([]byte, error) {
+	resp, err := e.kmsService.Projects.Locations.KeyRings.CryptoKeys.Encrypt(e.keyName,
+		&kms.EncryptRequest{Plaintext: base64.StdEncoding.EncodeToString(dek)}).Context(e.ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Ciphertext)
+}
+
+func (e *envelopeCipher) unwrapDEK(wrapped []byte) ([]byte, error) {
+	resp, err := e.kmsService.Projects.Locations.KeyRings.CryptoKeys.Decrypt(e.keyName,
+		&kms.DecryptRequest{Ciphertext: base64.StdEncoding.EncodeToString(wrapped)}).Context(e.ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Plaintext)
+}
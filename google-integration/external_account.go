@@ -0,0 +1,269 @@
+package googleintegration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+)
+
+const (
+	stsTokenExchangeURL      = "https://sts.googleapis.com/v1/token"
+	stsGrantType             = "urn:ietf:params:oauth:grant-type:token-exchange"
+	stsRequestedTokenType    = "urn:ietf:params:oauth:token-type:access_token"
+	iamCredentialsGenTokenFmt = "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/%s:generateAccessToken"
+)
+
+// SubjectTokenType identifies the format of the subject token presented to
+// the STS token-exchange endpoint.
+type SubjectTokenType string
+
+const (
+	// SubjectTokenTypeJWT is used for OIDC-based providers (GitHub Actions,
+	// Kubernetes, Azure AD).
+	SubjectTokenTypeJWT SubjectTokenType = "urn:ietf:params:oauth:token-type:jwt"
+	// SubjectTokenTypeAWS4 is used when the subject token is an AWS
+	// SigV4-signed GetCallerIdentity request (AWS workload identity).
+	SubjectTokenTypeAWS4 SubjectTokenType = "urn:ietf:params:oauth:token-type:aws4_request"
+)
+
+// SubjectTokenSupplier produces the subject token that will be exchanged
+// for a Google Cloud access token. Implementations can read from a file,
+// fetch a URL, shell out to an executable, sign an AWS IMDSv2 request, or
+// hold the token in memory.
+type SubjectTokenSupplier interface {
+	SubjectToken(ctx context.Context) (string, error)
+}
+
+// SubjectTokenFunc adapts a plain function to the SubjectTokenSupplier
+// interface, mirroring the http.HandlerFunc pattern.
+type SubjectTokenFunc func(ctx context.Context) (string, error)
+
+// SubjectToken implements SubjectTokenSupplier.
+func (f SubjectTokenFunc) SubjectToken(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// ExternalAccountConfig configures the Workload Identity Federation flow so
+// the bridge can authenticate to Google Cloud from AWS, Azure, GitHub
+// Actions, or Kubernetes without a service-account JSON key.
+type ExternalAccountConfig struct {
+	// Audience identifies the workload identity pool provider, e.g.
+	// "//iam.googleapis.com/projects/123/locations/global/workloadIdentityPools/my-pool/providers/my-provider".
+	Audience string
+	// SubjectTokenType is the format of the token SubjectTokenSupplier
+	// returns.
+	SubjectTokenType SubjectTokenType
+	// SubjectTokenSupplier produces the external identity token.
+	SubjectTokenSupplier SubjectTokenSupplier
+	// ServiceAccountToImpersonate, if set, is the email of a service
+	// account to impersonate via IAM Credentials generateAccessToken after
+	// the STS exchange, scoping the final token down to that identity.
+	ServiceAccountToImpersonate string
+	// Scopes requested for the final access token.
+	Scopes []string
+}
+
+// externalAccountTokenSource implements oauth2.TokenSource on top of the STS
+// token-exchange flow described in ExternalAccountConfig.
+type externalAccountTokenSource struct {
+	ctx        context.Context
+	cfg        ExternalAccountConfig
+	httpClient *http.Client
+}
+
+func newExternalAccountTokenSource(ctx context.Context, cfg ExternalAccountConfig) *externalAccountTokenSource {
+	return &externalAccountTokenSource{
+		ctx:        ctx,
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type stsTokenResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+}
+
+type generateAccessTokenResponse struct {
+	AccessToken string `json:"accessToken"`
+	ExpireTime  string `json:"expireTime"`
+}
+
+// Token exchanges the subject token for a Google Cloud access token and, if
+// configured, impersonates a service account to scope it down further.
+func (s *externalAccountTokenSource) Token() (*oauth2.Token, error) {
+	subjectToken, err := s.cfg.SubjectTokenSupplier.SubjectToken(s.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain subject token: %w", err)
+	}
+
+	stsToken, err := s.exchangeSubjectToken(subjectToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange subject token at STS: %w", err)
+	}
+
+	if s.cfg.ServiceAccountToImpersonate == "" {
+		return &oauth2.Token{
+			AccessToken: stsToken.AccessToken,
+			TokenType:   stsToken.TokenType,
+			Expiry:      time.Now().Add(time.Duration(stsToken.ExpiresIn) * time.Second),
+		}, nil
+	}
+
+	impersonated, err := s.impersonateServiceAccount(stsToken.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to impersonate service account %s: %w", s.cfg.ServiceAccountToImpersonate, err)
+	}
+
+	expiry, err := time.Parse(time.RFC3339, impersonated.ExpireTime)
+	if err != nil {
+		expiry = time.Now().Add(1 * time.Hour)
+	}
+
+	return &oauth2.Token{
+		AccessToken: impersonated.AccessToken,
+		TokenType:   "Bearer",
+		Expiry:      expiry,
+	}, nil
+}
+
+func (s *externalAccountTokenSource) exchangeSubjectToken(subjectToken string) (*stsTokenResponse, error) {
+	scope := "https://www.googleapis.com/auth/cloud-platform"
+	if len(s.cfg.Scopes) > 0 {
+		scope = joinScopes(s.cfg.Scopes)
+	}
+
+	form := url.Values{
+		"audience":             {s.cfg.Audience},
+		"grant_type":           {stsGrantType},
+		"requested_token_type": {stsRequestedTokenType},
+		"subject_token_type":   {string(s.cfg.SubjectTokenType)},
+		"subject_token":        {subjectToken},
+		"scope":                {scope},
+	}
+
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, stsTokenExchangeURL, bytes.NewBufferString(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sts token exchange returned status %d", resp.StatusCode)
+	}
+
+	var tokenResp stsTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to decode sts response: %w", err)
+	}
+
+	return &tokenResp, nil
+}
+
+func (s *externalAccountTokenSource) impersonateServiceAccount(federatedToken string) (*generateAccessTokenResponse, error) {
+	endpoint := fmt.Sprintf(iamCredentialsGenTokenFmt, s.cfg.ServiceAccountToImpersonate)
+
+	scopes := s.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"https://www.googleapis.com/auth/cloud-platform"}
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"scope":    scopes,
+		"lifetime": "3600s",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+federatedToken)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("generateAccessToken returned status %d", resp.StatusCode)
+	}
+
+	var tokenResp generateAccessTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to decode generateAccessToken response: %w", err)
+	}
+
+	return &tokenResp, nil
+}
+
+func joinScopes(scopes []string) string {
+	out := ""
+	for i, s := range scopes {
+		if i > 0 {
+			out += " "
+		}
+		out += s
+	}
+	return out
+}
+
+// NewGoogleAuthManagerFromExternalAccount builds a GoogleAuthManager backed
+// by Workload Identity Federation instead of a service-account JSON key,
+// allowing the bridge to run on AWS, Azure, GitHub Actions, or Kubernetes.
+func NewGoogleAuthManagerFromExternalAccount(cfg ExternalAccountConfig) (*GoogleAuthManager, error) {
+	ctx := context.Background()
+
+	if cfg.Audience == "" {
+		return nil, fmt.Errorf("external account config requires an Audience")
+	}
+	if cfg.SubjectTokenSupplier == nil {
+		return nil, fmt.Errorf("external account config requires a SubjectTokenSupplier")
+	}
+
+	tokenSource := oauth2.ReuseTokenSource(nil, newExternalAccountTokenSource(ctx, cfg))
+
+	services, err := newGoogleAPIServices(ctx, option.WithTokenSource(tokenSource))
+	if err != nil {
+		return nil, err
+	}
+
+	gam := &GoogleAuthManager{
+		externalAccountSource: tokenSource,
+		serviceAccount:        &ServiceAccountConfig{ClientEmail: cfg.ServiceAccountToImpersonate},
+		oauth2Service:         services.oauth2Service,
+		iamService:            services.iamService,
+		resourceManager:       services.resourceManager,
+		storageService:        services.storageService,
+		pubsubService:         services.pubsubService,
+		bigqueryService:       services.bigqueryService,
+		runService:            services.runService,
+		tokenStore:            NewMemoryTokenStore(),
+		permCache:             newPermissionCache(permissionCacheTTL),
+		groupCache:            newGroupMembershipCache(defaultGroupCacheTTL),
+		resourceConfig:        loadDefaultResourceConfig(),
+		ctx:                   ctx,
+	}
+	startTokenStorePruning(ctx, gam.tokenStore, tokenStorePruneInterval)
+
+	return gam, nil
+}
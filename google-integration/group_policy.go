@@ -0,0 +1,190 @@
+package googleintegration
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2/google"
+	"golang.org/x/oauth2/jwt"
+	admin "google.golang.org/api/admin/directory/v1"
+	"google.golang.org/api/option"
+	"gopkg.in/yaml.v3"
+)
+
+// googleJWTConfigWithSubject builds a JWT config from service-account JSON
+// that impersonates subject via domain-wide delegation, as required to call
+// Workspace Admin SDK APIs on behalf of end users.
+func googleJWTConfigWithSubject(jsonKey []byte, subject string, scopes ...string) (*jwt.Config, error) {
+	cfg, err := google.JWTConfigFromJSON(jsonKey, scopes...)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Subject = subject
+	return cfg, nil
+}
+
+// defaultGroupPolicyPath is where the group→(repo, action) authorization
+// policy is loaded from when the caller doesn't override it.
+const defaultGroupPolicyPath = "config/group_policy.yaml"
+
+// defaultGroupCacheTTL bounds how long a resolved set of group memberships
+// is reused before the Directory API is queried again.
+const defaultGroupCacheTTL = 5 * time.Minute
+
+// RepoAction identifies a single (repository, action) pair.
+type RepoAction struct {
+	Repository string `yaml:"repository" json:"repository"`
+	Action     string `yaml:"action" json:"action"`
+}
+
+// GroupPermissionPolicy maps a Google Workspace group email to the
+// (repository, action) tuples its members are allowed to invoke.
+type GroupPermissionPolicy struct {
+	Groups map[string][]RepoAction `yaml:"groups" json:"groups"`
+}
+
+// LoadGroupPermissionPolicy reads a YAML or JSON group authorization policy
+// from disk.
+func LoadGroupPermissionPolicy(path string) (*GroupPermissionPolicy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read group policy: %w", err)
+	}
+
+	policy := &GroupPermissionPolicy{}
+	if err := yaml.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse group policy: %w", err)
+	}
+
+	return policy, nil
+}
+
+// allows reports whether any of the caller's groups are authorized for the
+// given (repository, action) pair.
+func (p *GroupPermissionPolicy) allows(groups []string, repository, action string) bool {
+	if p == nil {
+		return false
+	}
+
+	for _, group := range groups {
+		for _, ra := range p.Groups[group] {
+			if ra.Repository == repository && ra.Action == action {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// groupMembershipCache memoizes a user's Workspace group memberships for
+// defaultGroupCacheTTL to avoid hammering the Directory API.
+type groupMembershipCache struct {
+	mu      sync.Mutex
+	entries map[string]groupCacheEntry
+	ttl     time.Duration
+}
+
+type groupCacheEntry struct {
+	groups    []string
+	expiresAt time.Time
+}
+
+func newGroupMembershipCache(ttl time.Duration) *groupMembershipCache {
+	return &groupMembershipCache{
+		entries: make(map[string]groupCacheEntry),
+		ttl:     ttl,
+	}
+}
+
+func (c *groupMembershipCache) get(userEmail string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, exists := c.entries[userEmail]
+	if !exists {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.entries, userEmail)
+		return nil, false
+	}
+	return entry.groups, true
+}
+
+func (c *groupMembershipCache) put(userEmail string, groups []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[userEmail] = groupCacheEntry{
+		groups:    groups,
+		expiresAt: time.Now().Add(c.ttl),
+	}
+}
+
+func (c *groupMembershipCache) evict(userEmail string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, userEmail)
+}
+
+// newDirectoryService builds an Admin SDK Directory client that
+// impersonates workspaceAdminSubject via domain-wide delegation, which is
+// required to call groups.list on behalf of arbitrary users.
+func newDirectoryService(gam *GoogleAuthManager, workspaceAdminSubject string) (*admin.Service, error) {
+	if workspaceAdminSubject == "" {
+		return nil, fmt.Errorf("domain-wide delegation requires a workspace admin subject")
+	}
+
+	jwtConfig, err := googleJWTConfigWithSubject([]byte(gam.serviceAccountJSON()), workspaceAdminSubject,
+		admin.AdminDirectoryGroupReadonlyScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build domain-wide delegation JWT config: %w", err)
+	}
+
+	service, err := admin.NewService(gam.ctx, option.WithTokenSource(jwtConfig.TokenSource(gam.ctx)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Directory service: %w", err)
+	}
+
+	return service, nil
+}
+
+// resolveUserGroups returns the Workspace groups userEmail belongs to,
+// consulting the TTL cache before calling the Directory API. groupCache is
+// initialized once in the constructor (alongside permCache) so concurrent
+// callers never race on setting it up.
+func (gam *GoogleAuthManager) resolveUserGroups(userEmail string) ([]string, error) {
+	if groups, hit := gam.groupCache.get(userEmail); hit {
+		return groups, nil
+	}
+
+	if gam.directoryService == nil {
+		return nil, fmt.Errorf("directory service not configured; use WithDomainWideDelegation")
+	}
+
+	resp, err := gam.directoryService.Groups.List().UserKey(userEmail).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups for %s: %w", userEmail, err)
+	}
+
+	groups := make([]string, 0, len(resp.Groups))
+	for _, g := range resp.Groups {
+		groups = append(groups, g.Email)
+	}
+
+	gam.groupCache.put(userEmail, groups)
+	return groups, nil
+}
+
+// RefreshGroupCache evicts the cached group memberships for userEmail,
+// forcing the next authorization check to re-query the Directory API. It's
+// intended to be called from a webhook when group membership changes.
+func (gam *GoogleAuthManager) RefreshGroupCache(userEmail string) {
+	gam.groupCache.evict(userEmail)
+	log.Printf("Refreshed group membership cache for %s", userEmail)
+}
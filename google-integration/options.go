@@ -0,0 +1,272 @@
+package googleintegration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"cloud.google.com/go/firestore"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/option"
+)
+
+// credentialSource enumerates the mutually exclusive ways a
+// GoogleAuthManager can be handed credentials.
+type credentialSource int
+
+const (
+	credentialSourceNone credentialSource = iota
+	credentialSourceFile
+	credentialSourceJSON
+	credentialSourceAccessToken
+	credentialSourceADC
+	credentialSourceTokenSource
+)
+
+// authManagerOptions accumulates the configuration built up by Option
+// values before NewGoogleAuthManager resolves them into Google API clients.
+type authManagerOptions struct {
+	source credentialSource
+
+	credentialsPath string
+	credentialsJSON []byte
+	accessToken     string
+	tokenSource     oauth2.TokenSource
+
+	scopes         []string
+	skipValidation bool
+
+	workspaceAdminSubject string
+	groupPolicyPath       string
+
+	tokenStore     tokenStoreKind
+	redisClient    *redis.Client
+	redisKeyPrefix string
+	firestoreClient *firestore.Client
+	firestoreCollection string
+	kmsKeyName     string
+}
+
+// tokenStoreKind selects which TokenStore backend NewGoogleAuthManager
+// wires up; see WithRedisTokenStore / WithFirestoreTokenStore.
+type tokenStoreKind int
+
+const (
+	tokenStoreMemory tokenStoreKind = iota
+	tokenStoreRedis
+	tokenStoreFirestore
+)
+
+// WithRedisTokenStore persists cached tokens in Redis, encrypted at rest
+// with a KMS-wrapped DEK, so they survive restarts and are shared across a
+// horizontally scaled bridge. keyPrefix namespaces the Redis keys and
+// kmsKeyName is the Cloud KMS CryptoKey resource name used to wrap the DEK.
+func WithRedisTokenStore(client *redis.Client, keyPrefix, kmsKeyName string) Option {
+	return func(o *authManagerOptions) {
+		o.tokenStore = tokenStoreRedis
+		o.redisClient = client
+		o.redisKeyPrefix = keyPrefix
+		o.kmsKeyName = kmsKeyName
+	}
+}
+
+// WithFirestoreTokenStore persists cached tokens in Firestore, encrypted at
+// rest with a KMS-wrapped DEK, reusing the bridge's existing GCP
+// credentials.
+func WithFirestoreTokenStore(client *firestore.Client, collection, kmsKeyName string) Option {
+	return func(o *authManagerOptions) {
+		o.tokenStore = tokenStoreFirestore
+		o.firestoreClient = client
+		o.firestoreCollection = collection
+		o.kmsKeyName = kmsKeyName
+	}
+}
+
+// Option configures a GoogleAuthManager created via NewGoogleAuthManager.
+// Exactly one credential-source option should be supplied; the last one
+// wins if more than one is passed.
+type Option func(*authManagerOptions)
+
+// WithCredentialsFile configures the manager to load a service-account
+// JSON key from disk. This is the traditional deployment path.
+func WithCredentialsFile(path string) Option {
+	return func(o *authManagerOptions) {
+		o.source = credentialSourceFile
+		o.credentialsPath = path
+	}
+}
+
+// WithCredentialsJSON configures the manager from raw service-account JSON
+// bytes, useful when the key is injected via a secrets manager rather than
+// mounted as a file.
+func WithCredentialsJSON(data []byte) Option {
+	return func(o *authManagerOptions) {
+		o.source = credentialSourceJSON
+		o.credentialsJSON = data
+	}
+}
+
+// WithAccessToken configures the manager to use a pre-minted OAuth2 access
+// token, for CI/CD environments where credentials are injected by an outer
+// system (e.g. Cloud Build, GitHub Actions with a short-lived token already
+// exchanged upstream).
+func WithAccessToken(token string) Option {
+	return func(o *authManagerOptions) {
+		o.source = credentialSourceAccessToken
+		o.accessToken = token
+	}
+}
+
+// WithApplicationDefaultCredentials configures the manager to resolve
+// Application Default Credentials via google.FindDefaultCredentials
+// (GOOGLE_APPLICATION_CREDENTIALS, gcloud user credentials, or the GCE/GKE
+// metadata server).
+func WithApplicationDefaultCredentials() Option {
+	return func(o *authManagerOptions) {
+		o.source = credentialSourceADC
+	}
+}
+
+// WithTokenSource configures the manager with a caller-supplied
+// oauth2.TokenSource, for bespoke credential flows that don't fit the
+// other options.
+func WithTokenSource(ts oauth2.TokenSource) Option {
+	return func(o *authManagerOptions) {
+		o.source = credentialSourceTokenSource
+		o.tokenSource = ts
+	}
+}
+
+// WithScopes overrides the default OAuth2 scopes requested for the manager.
+func WithScopes(scopes ...string) Option {
+	return func(o *authManagerOptions) {
+		o.scopes = scopes
+	}
+}
+
+// WithDomainWideDelegation enables Workspace group-based authorization:
+// workspaceAdminSubject is a Workspace super admin email the service
+// account impersonates (via domain-wide delegation) to call the Admin SDK
+// Directory API's groups.list on behalf of arbitrary users. Only valid
+// with WithCredentialsFile/WithCredentialsJSON, since it requires a
+// service-account private key to sign the impersonated JWT.
+func WithDomainWideDelegation(workspaceAdminSubject string) Option {
+	return func(o *authManagerOptions) {
+		o.workspaceAdminSubject = workspaceAdminSubject
+	}
+}
+
+// WithGroupPolicyFile overrides the default path the group authorization
+// policy (see GroupPermissionPolicy) is loaded from.
+func WithGroupPolicyFile(path string) Option {
+	return func(o *authManagerOptions) {
+		o.groupPolicyPath = path
+	}
+}
+
+// WithoutStartupValidation skips the fail-fast Tokeninfo dial performed at
+// construction time. Intended for tests/tooling, not production use.
+func WithoutStartupValidation() Option {
+	return func(o *authManagerOptions) {
+		o.skipValidation = true
+	}
+}
+
+func defaultScopes() []string {
+	return []string{
+		"https://www.googleapis.com/auth/cloud-platform",
+		"https://www.googleapis.com/auth/userinfo.email",
+		"https://www.googleapis.com/auth/userinfo.profile",
+	}
+}
+
+// resolveClientOption turns the accumulated options into the
+// option.ClientOption the Google API client libraries expect, a
+// ServiceAccountConfig describing the identity in use where known, and,
+// for the credential sources that don't carry a JSON private key (access
+// token, ADC, custom token source), the oauth2.TokenSource GetServiceAccountToken
+// should use directly instead of the JWT-from-JSON flow.
+func resolveClientOption(ctx context.Context, o *authManagerOptions) (option.ClientOption, *ServiceAccountConfig, oauth2.TokenSource, error) {
+	scopes := o.scopes
+	if len(scopes) == 0 {
+		scopes = defaultScopes()
+	}
+
+	switch o.source {
+	case credentialSourceFile:
+		serviceAccount, err := loadServiceAccountConfig(o.credentialsPath)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to load service account config: %w", err)
+		}
+		return option.WithCredentialsFile(o.credentialsPath), serviceAccount, nil, nil
+
+	case credentialSourceJSON:
+		serviceAccount, err := parseServiceAccountJSON(o.credentialsJSON)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to parse service account JSON: %w", err)
+		}
+		return option.WithCredentialsJSON(o.credentialsJSON), serviceAccount, nil, nil
+
+	case credentialSourceAccessToken:
+		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: o.accessToken})
+		return option.WithTokenSource(ts), &ServiceAccountConfig{}, ts, nil
+
+	case credentialSourceADC:
+		creds, err := google.FindDefaultCredentials(ctx, scopes...)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to resolve application default credentials: %w", err)
+		}
+		serviceAccount := &ServiceAccountConfig{ProjectID: creds.ProjectID}
+		return option.WithCredentials(creds), serviceAccount, creds.TokenSource, nil
+
+	case credentialSourceTokenSource:
+		if o.tokenSource == nil {
+			return nil, nil, nil, fmt.Errorf("WithTokenSource was used with a nil token source")
+		}
+		return option.WithTokenSource(o.tokenSource), &ServiceAccountConfig{}, o.tokenSource, nil
+
+	default:
+		return nil, nil, nil, fmt.Errorf("no credential source configured; pass a GoogleAuthManager Option")
+	}
+}
+
+// parseServiceAccountJSON unmarshals raw service-account JSON bytes into a
+// ServiceAccountConfig.
+func parseServiceAccountJSON(data []byte) (*ServiceAccountConfig, error) {
+	var cfg ServiceAccountConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// loadServiceAccountConfig reads and parses a service-account JSON key file
+// from disk.
+func loadServiceAccountConfig(credentialsPath string) (*ServiceAccountConfig, error) {
+	data, err := ioutil.ReadFile(credentialsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file %s: %w", credentialsPath, err)
+	}
+	return parseServiceAccountJSON(data)
+}
+
+// validateStartupCredentials dials oauth2.tokeninfo once with a freshly
+// minted token so misconfigured credentials fail fast at startup rather
+// than on the first authenticated request.
+func (gam *GoogleAuthManager) validateStartupCredentials(scopes []string) error {
+	token, err := gam.GetServiceAccountToken(scopes)
+	if err != nil {
+		return fmt.Errorf("failed to mint startup validation token: %w", err)
+	}
+
+	call := gam.oauth2Service.Tokeninfo()
+	call.AccessToken(token.AccessToken)
+	if _, err := call.Do(); err != nil {
+		return fmt.Errorf("startup credential validation failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,293 @@
+package googleintegration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/pubsub/v1"
+	"google.golang.org/api/run/v1"
+	"gopkg.in/yaml.v3"
+)
+
+// ResourceBinding describes the Google Cloud resource that backs a single
+// (repository, action) pair and the permissions that must be granted on it.
+type ResourceBinding struct {
+	ResourceType string   `yaml:"resource_type" json:"resource_type"` // project, bucket, dataset, service, topic
+	ResourceName string   `yaml:"resource_name" json:"resource_name"`
+	Permissions  []string `yaml:"permissions" json:"permissions"`
+}
+
+// RepoResourceConfig is the repo→resource mapping loaded from disk at
+// startup so operators can register new repositories without recompiling.
+type RepoResourceConfig struct {
+	Repositories map[string]map[string]ResourceBinding `yaml:"repositories" json:"repositories"`
+}
+
+// LoadResourceConfig reads a YAML or JSON repo→resource mapping file. The
+// format is chosen based on the file extension.
+func LoadResourceConfig(path string) (*RepoResourceConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read resource config: %w", err)
+	}
+
+	cfg := &RepoResourceConfig{}
+	if strings.HasSuffix(path, ".json") {
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse resource config as json: %w", err)
+		}
+		return cfg, nil
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse resource config as yaml: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// permissionCacheEntry is a single TTL-bounded cache slot.
+type permissionCacheEntry struct {
+	permissions []string
+	expiresAt   time.Time
+}
+
+// permissionCache memoizes TestIamPermissions results keyed by
+// (userEmail, resource, requested permission set) so repeated checks for
+// the same caller don't burn API quota.
+type permissionCache struct {
+	mu      sync.Mutex
+	entries map[string]permissionCacheEntry
+	ttl     time.Duration
+}
+
+func newPermissionCache(ttl time.Duration) *permissionCache {
+	return &permissionCache{
+		entries: make(map[string]permissionCacheEntry),
+		ttl:     ttl,
+	}
+}
+
+func permissionCacheKey(userEmail, resource string, permissions []string) string {
+	sorted := append([]string(nil), permissions...)
+	for i := 1; i < len(sorted); i++ {
+		for j := i; j > 0 && sorted[j-1] > sorted[j]; j-- {
+			sorted[j-1], sorted[j] = sorted[j], sorted[j-1]
+		}
+	}
+	return userEmail + "|" + resource + "|" + strings.Join(sorted, ",")
+}
+
+func (c *permissionCache) get(key string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, exists := c.entries[key]
+	if !exists {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.permissions, true
+}
+
+func (c *permissionCache) put(key string, permissions []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = permissionCacheEntry{
+		permissions: permissions,
+		expiresAt:   time.Now().Add(c.ttl),
+	}
+}
+
+// CheckRepositoryPermissions resolves the Google Cloud resource backing the
+// given (repository, action) pair and calls TestIamPermissions to find the
+// intersection of requested and actually granted permissions.
+func (gam *GoogleAuthManager) CheckRepositoryPermissions(userEmail, repository, action string) ([]string, error) {
+	if gam.resourceConfig == nil {
+		return []string{"basic.read"}, nil
+	}
+
+	repoBindings, exists := gam.resourceConfig.Repositories[repository]
+	if !exists {
+		return []string{"basic.read"}, nil // Default permissions for unknown repositories
+	}
+
+	binding, exists := repoBindings[action]
+	if !exists {
+		return []string{"basic.read"}, nil // Default permissions for unknown actions
+	}
+
+	cacheKey := permissionCacheKey(userEmail, binding.ResourceName, binding.Permissions)
+	if cached, hit := gam.permCache.get(cacheKey); hit {
+		return cached, nil
+	}
+
+	granted, err := gam.testIamPermissions(binding, binding.Permissions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to test IAM permissions for %s/%s: %w", repository, action, err)
+	}
+
+	if len(granted) == 0 && gam.groupPolicy != nil {
+		granted = gam.grantedByGroupPolicy(userEmail, repository, action, binding.Permissions)
+	}
+
+	log.Printf("Checked IAM permissions for user %s on %s %s: requested %v, granted %v",
+		userEmail, binding.ResourceType, binding.ResourceName, binding.Permissions, granted)
+
+	gam.permCache.put(cacheKey, granted)
+	return granted, nil
+}
+
+// grantedByGroupPolicy authorizes userEmail for (repository, action) based
+// on Workspace group membership when the direct IAM check above didn't
+// grant anything. It returns the full requested permission set if any of
+// the user's groups are allowed, and nil otherwise.
+func (gam *GoogleAuthManager) grantedByGroupPolicy(userEmail, repository, action string, requested []string) []string {
+	groups, err := gam.resolveUserGroups(userEmail)
+	if err != nil {
+		log.Printf("Warning: failed to resolve groups for %s: %v", userEmail, err)
+		return nil
+	}
+
+	if gam.groupPolicy.allows(groups, repository, action) {
+		return requested
+	}
+
+	return nil
+}
+
+// testIamPermissions dispatches TestIamPermissions to the correct Google
+// Cloud API client based on the resource type, returning only the
+// permissions that were actually granted.
+func (gam *GoogleAuthManager) testIamPermissions(binding ResourceBinding, requested []string) ([]string, error) {
+	switch binding.ResourceType {
+	case "project":
+		resp, err := gam.resourceManager.Projects.TestIamPermissions(binding.ResourceName,
+			&cloudresourcemanager.TestIamPermissionsRequest{Permissions: requested}).Do()
+		if err != nil {
+			return nil, err
+		}
+		return resp.Permissions, nil
+
+	case "bucket":
+		if gam.storageService == nil {
+			return nil, fmt.Errorf("storage client not initialized")
+		}
+		resp, err := gam.storageService.Buckets.TestIamPermissions(binding.ResourceName, requested).Do()
+		if err != nil {
+			return nil, err
+		}
+		return resp.Permissions, nil
+
+	case "table":
+		if gam.bigqueryService == nil {
+			return nil, fmt.Errorf("bigquery client not initialized")
+		}
+		resp, err := gam.bigqueryService.Tables.TestIamPermissions(binding.ResourceName,
+			&bigquery.TestIamPermissionsRequest{Permissions: requested}).Do()
+		if err != nil {
+			return nil, err
+		}
+		return resp.Permissions, nil
+
+	case "dataset":
+		return gam.testDatasetIamPermissions(binding, requested)
+
+	case "topic":
+		if gam.pubsubService == nil {
+			return nil, fmt.Errorf("pubsub client not initialized")
+		}
+		resp, err := gam.pubsubService.Projects.Topics.TestIamPermissions(binding.ResourceName,
+			&pubsub.TestIamPermissionsRequest{Permissions: requested}).Do()
+		if err != nil {
+			return nil, err
+		}
+		return resp.Permissions, nil
+
+	case "service":
+		if gam.runService == nil {
+			return nil, fmt.Errorf("cloud run client not initialized")
+		}
+		resp, err := gam.runService.Projects.Locations.Services.TestIamPermissions(binding.ResourceName,
+			&run.TestIamPermissionsRequest{Permissions: requested}).Do()
+		if err != nil {
+			return nil, err
+		}
+		return resp.Permissions, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported resource type %q", binding.ResourceType)
+	}
+}
+
+// bigqueryDatasetRolePermissions maps each legacy BigQuery dataset ACL role
+// to the permissions it implies. Datasets, unlike tables, don't expose
+// TestIamPermissions; access is instead governed by WRITER/READER/OWNER ACL
+// entries (see testDatasetIamPermissions).
+var bigqueryDatasetRolePermissions = map[string][]string{
+	"OWNER":  {"bigquery.jobs.create", "bigquery.tables.updateData", "bigquery.tables.getData", "storage.objects.read", "storage.objects.create"},
+	"WRITER": {"bigquery.jobs.create", "bigquery.tables.updateData", "bigquery.tables.getData", "storage.objects.read", "storage.objects.create"},
+	"READER": {"bigquery.jobs.create", "bigquery.tables.getData", "storage.objects.read"},
+}
+
+// testDatasetIamPermissions authorizes a dataset-typed ResourceBinding by
+// looking up the calling service account's role in the dataset's ACL,
+// since BigQuery datasets (unlike tables) don't implement
+// TestIamPermissions. It returns the subset of requested permissions
+// implied by that role.
+func (gam *GoogleAuthManager) testDatasetIamPermissions(binding ResourceBinding, requested []string) ([]string, error) {
+	if gam.bigqueryService == nil {
+		return nil, fmt.Errorf("bigquery client not initialized")
+	}
+
+	dataset, err := gam.bigqueryService.Datasets.Get(gam.serviceAccount.ProjectID, binding.ResourceName).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dataset %s: %w", binding.ResourceName, err)
+	}
+
+	role := datasetACLRole(dataset.Access, gam.serviceAccount.ClientEmail)
+	if role == "" {
+		return nil, nil
+	}
+
+	return intersectPermissions(requested, bigqueryDatasetRolePermissions[role]), nil
+}
+
+// datasetACLRole returns the role granted to email in access, or "" if
+// email has no entry.
+func datasetACLRole(access []*bigquery.DatasetAccess, email string) string {
+	for _, entry := range access {
+		if entry.UserByEmail == email || entry.GroupByEmail == email {
+			return entry.Role
+		}
+	}
+	return ""
+}
+
+// intersectPermissions returns the permissions in requested that also
+// appear in granted, preserving requested's order.
+func intersectPermissions(requested, granted []string) []string {
+	grantedSet := make(map[string]bool, len(granted))
+	for _, p := range granted {
+		grantedSet[p] = true
+	}
+
+	var result []string
+	for _, p := range requested {
+		if grantedSet[p] {
+			result = append(result, p)
+		}
+	}
+	return result
+}
@@ -0,0 +1,311 @@
+package googleintegration
+
+import (
+	"context"
+	"encoding/base64"
+	"sync"
+	"time"
+
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+	kms "google.golang.org/api/cloudkms/v1"
+	"google.golang.org/api/option"
+)
+
+// tokenStorePruneInterval is how often the background goroutine sweeps the
+// configured TokenStore for expired entries.
+const tokenStorePruneInterval = 5 * time.Minute
+
+// buildTokenStore resolves the TokenStore backend selected via Option
+// (WithRedisTokenStore/WithFirestoreTokenStore), defaulting to an
+// in-memory store when neither was requested.
+func buildTokenStore(ctx context.Context, clientOption option.ClientOption, o *authManagerOptions) (TokenStore, error) {
+	switch o.tokenStore {
+	case tokenStoreRedis:
+		envelope, err := newEnvelopeCipherFromClientOption(ctx, clientOption, o.kmsKeyName)
+		if err != nil {
+			return nil, err
+		}
+		return NewRedisTokenStore(ctx, o.redisClient, o.redisKeyPrefix, envelope), nil
+
+	case tokenStoreFirestore:
+		envelope, err := newEnvelopeCipherFromClientOption(ctx, clientOption, o.kmsKeyName)
+		if err != nil {
+			return nil, err
+		}
+		return NewFirestoreTokenStore(ctx, o.firestoreClient, o.firestoreCollection, envelope), nil
+
+	default:
+		return NewMemoryTokenStore(), nil
+	}
+}
+
+func newEnvelopeCipherFromClientOption(ctx context.Context, clientOption option.ClientOption, kmsKeyName string) (*envelopeCipher, error) {
+	if kmsKeyName == "" {
+		return nil, fmt.Errorf("a KMS key name is required to encrypt cached tokens at rest")
+	}
+
+	kmsService, err := kms.NewService(ctx, clientOption)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create KMS service: %w", err)
+	}
+
+	return newEnvelopeCipher(ctx, kmsService, kmsKeyName), nil
+}
+
+var (
+	tokenCacheHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "google_auth_token_cache_hits_total",
+		Help: "Number of CachedToken lookups served from the token store.",
+	}, []string{"backend"})
+
+	tokenCacheMisses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "google_auth_token_cache_misses_total",
+		Help: "Number of CachedToken lookups that found no valid token.",
+	}, []string{"backend"})
+
+	tokenCacheEvictions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "google_auth_token_cache_evictions_total",
+		Help: "Number of cached tokens removed for being expired or pruned.",
+	}, []string{"backend"})
+)
+
+// TokenStore abstracts where CachedTokens are persisted so the bridge can
+// scale horizontally without losing the benefit of token reuse across
+// instances. CacheToken/GetCachedToken on GoogleAuthManager are thin
+// wrappers over this interface.
+type TokenStore interface {
+	Get(userID string) (*CachedToken, bool)
+	Put(userID string, token *CachedToken)
+	Delete(userID string)
+	// Prune removes all entries that expired as of now.
+	Prune(now time.Time)
+}
+
+// startTokenStorePruning runs store.Prune on a fixed interval until ctx is
+// canceled, so expired entries don't accumulate indefinitely in stores that
+// don't support native TTLs (e.g. MemoryTokenStore).
+func startTokenStorePruning(ctx context.Context, store TokenStore, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				store.Prune(time.Now())
+			}
+		}
+	}()
+}
+
+// MemoryTokenStore is the original per-process in-memory token cache,
+// reimplemented against the TokenStore interface.
+type MemoryTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]*CachedToken
+}
+
+// NewMemoryTokenStore creates an empty in-memory token store.
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: make(map[string]*CachedToken)}
+}
+
+func (s *MemoryTokenStore) Get(userID string) (*CachedToken, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cached, exists := s.tokens[userID]
+	if !exists {
+		tokenCacheMisses.WithLabelValues("memory").Inc()
+		return nil, false
+	}
+	if time.Now().After(cached.ExpiresAt) {
+		delete(s.tokens, userID)
+		tokenCacheMisses.WithLabelValues("memory").Inc()
+		tokenCacheEvictions.WithLabelValues("memory").Inc()
+		return nil, false
+	}
+
+	tokenCacheHits.WithLabelValues("memory").Inc()
+	return cached, true
+}
+
+func (s *MemoryTokenStore) Put(userID string, token *CachedToken) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[userID] = token
+}
+
+func (s *MemoryTokenStore) Delete(userID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, userID)
+}
+
+func (s *MemoryTokenStore) Prune(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for userID, cached := range s.tokens {
+		if now.After(cached.ExpiresAt) {
+			delete(s.tokens, userID)
+			tokenCacheEvictions.WithLabelValues("memory").Inc()
+		}
+	}
+}
+
+// RedisTokenStore persists CachedTokens in Redis so they survive restarts
+// and are shared across a horizontally scaled bridge. Tokens are encrypted
+// at rest with a KMS-wrapped DEK before being written.
+type RedisTokenStore struct {
+	client    *redis.Client
+	keyPrefix string
+	envelope  *envelopeCipher
+	ctx       context.Context
+}
+
+// NewRedisTokenStore creates a Redis-backed TokenStore. keyPrefix
+// namespaces keys (e.g. "bridge:auth:tokens:") so the store can share a
+// Redis instance with other subsystems.
+func NewRedisTokenStore(ctx context.Context, client *redis.Client, keyPrefix string, envelope *envelopeCipher) *RedisTokenStore {
+	return &RedisTokenStore{client: client, keyPrefix: keyPrefix, envelope: envelope, ctx: ctx}
+}
+
+func (s *RedisTokenStore) key(userID string) string {
+	return s.keyPrefix + userID
+}
+
+func (s *RedisTokenStore) Get(userID string) (*CachedToken, bool) {
+	raw, err := s.client.Get(s.ctx, s.key(userID)).Result()
+	if err == redis.Nil {
+		tokenCacheMisses.WithLabelValues("redis").Inc()
+		return nil, false
+	}
+	if err != nil {
+		tokenCacheMisses.WithLabelValues("redis").Inc()
+		return nil, false
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		tokenCacheMisses.WithLabelValues("redis").Inc()
+		return nil, false
+	}
+
+	token, err := s.envelope.decryptToken(ciphertext)
+	if err != nil {
+		tokenCacheMisses.WithLabelValues("redis").Inc()
+		return nil, false
+	}
+
+	tokenCacheHits.WithLabelValues("redis").Inc()
+	return token, true
+}
+
+func (s *RedisTokenStore) Put(userID string, token *CachedToken) {
+	ciphertext, err := s.envelope.encryptToken(token)
+	if err != nil {
+		return
+	}
+
+	ttl := time.Until(token.ExpiresAt)
+	if ttl <= 0 {
+		return
+	}
+
+	s.client.Set(s.ctx, s.key(userID), base64.StdEncoding.EncodeToString(ciphertext), ttl)
+}
+
+func (s *RedisTokenStore) Delete(userID string) {
+	s.client.Del(s.ctx, s.key(userID))
+}
+
+// Prune is a no-op for Redis: entries carry a native TTL and expire on
+// their own.
+func (s *RedisTokenStore) Prune(now time.Time) {}
+
+// firestoreTokenDoc is the on-disk shape of a cached token in Firestore.
+// Token and Scopes are stored as a single KMS-wrapped ciphertext blob.
+type firestoreTokenDoc struct {
+	Ciphertext []byte    `firestore:"ciphertext"`
+	ExpiresAt  time.Time `firestore:"expires_at"`
+}
+
+// FirestoreTokenStore persists CachedTokens in Firestore, reusing the
+// bridge's existing GCP credentials.
+type FirestoreTokenStore struct {
+	client     *firestore.Client
+	collection string
+	envelope   *envelopeCipher
+	ctx        context.Context
+}
+
+// NewFirestoreTokenStore creates a Firestore-backed TokenStore under the
+// given collection (e.g. "bridge_auth_tokens").
+func NewFirestoreTokenStore(ctx context.Context, client *firestore.Client, collection string, envelope *envelopeCipher) *FirestoreTokenStore {
+	return &FirestoreTokenStore{client: client, collection: collection, envelope: envelope, ctx: ctx}
+}
+
+func (s *FirestoreTokenStore) Get(userID string) (*CachedToken, bool) {
+	snap, err := s.client.Collection(s.collection).Doc(userID).Get(s.ctx)
+	if err != nil {
+		tokenCacheMisses.WithLabelValues("firestore").Inc()
+		return nil, false
+	}
+
+	var doc firestoreTokenDoc
+	if err := snap.DataTo(&doc); err != nil {
+		tokenCacheMisses.WithLabelValues("firestore").Inc()
+		return nil, false
+	}
+
+	if time.Now().After(doc.ExpiresAt) {
+		s.Delete(userID)
+		tokenCacheMisses.WithLabelValues("firestore").Inc()
+		tokenCacheEvictions.WithLabelValues("firestore").Inc()
+		return nil, false
+	}
+
+	token, err := s.envelope.decryptToken(doc.Ciphertext)
+	if err != nil {
+		tokenCacheMisses.WithLabelValues("firestore").Inc()
+		return nil, false
+	}
+
+	tokenCacheHits.WithLabelValues("firestore").Inc()
+	return token, true
+}
+
+func (s *FirestoreTokenStore) Put(userID string, token *CachedToken) {
+	ciphertext, err := s.envelope.encryptToken(token)
+	if err != nil {
+		return
+	}
+
+	s.client.Collection(s.collection).Doc(userID).Set(s.ctx, firestoreTokenDoc{
+		Ciphertext: ciphertext,
+		ExpiresAt:  token.ExpiresAt,
+	})
+}
+
+func (s *FirestoreTokenStore) Delete(userID string) {
+	s.client.Collection(s.collection).Doc(userID).Delete(s.ctx)
+}
+
+func (s *FirestoreTokenStore) Prune(now time.Time) {
+	docs, err := s.client.Collection(s.collection).Where("expires_at", "<", now).Documents(s.ctx).GetAll()
+	if err != nil {
+		return
+	}
+	for _, doc := range docs {
+		doc.Ref.Delete(s.ctx)
+		tokenCacheEvictions.WithLabelValues("firestore").Inc()
+	}
+}
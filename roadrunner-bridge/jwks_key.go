@@ -0,0 +1,31 @@
+package main
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+)
+
+// jwkToRSAPublicKey decodes a JWK's base64url-encoded modulus/exponent
+// into a *rsa.PublicKey usable for JWT signature verification.
+func jwkToRSAPublicKey(key *jwksKey) (*rsa.PublicKey, error) {
+	if key.kty != "RSA" {
+		return nil, fmt.Errorf("unsupported JWK key type %q", key.kty)
+	}
+
+	nBytes, err := base64.RawURLEncoding.DecodeString(key.n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode JWK modulus: %w", err)
+	}
+
+	eBytes, err := base64.RawURLEncoding.DecodeString(key.e)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode JWK exponent: %w", err)
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nBytes),
+		E: int(new(big.Int).SetBytes(eBytes).Int64()),
+	}, nil
+}
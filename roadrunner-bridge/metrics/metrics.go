@@ -0,0 +1,54 @@
+// Package metrics exposes the Prometheus instrumentation for the
+// RoadRunner bridge gateway: RED-style request metrics (rate, errors,
+// duration) plus gauges for repository health and in-flight requests.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// RequestsTotal counts every request the bridge handles, labeled by
+	// repository, action, HTTP method, and response status.
+	RequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bridge_requests_total",
+		Help: "Total number of bridge requests.",
+	}, []string{"repository", "action", "method", "status"})
+
+	// RequestDuration tracks end-to-end request latency in seconds.
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bridge_request_duration_seconds",
+		Help:    "Bridge request duration in seconds.",
+		Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"repository", "action"})
+
+	// RepositoryHealthy reports the last-observed health of each upstream
+	// repository (1 = healthy, 0 = unhealthy).
+	RepositoryHealthy = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "bridge_repository_healthy",
+		Help: "Whether a bridged repository's last health check succeeded.",
+	}, []string{"name"})
+
+	// InflightRequests counts requests currently being handled.
+	InflightRequests = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "bridge_inflight_requests",
+		Help: "Number of bridge requests currently in flight.",
+	})
+
+	// UpstreamErrors counts errors returned by a specific upstream
+	// repository, independent of the HTTP status sent back to the client.
+	UpstreamErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bridge_upstream_errors_total",
+		Help: "Total number of errors encountered routing to an upstream repository.",
+	}, []string{"repository"})
+)
+
+// SetRepositoryHealthy records the current health of a bridged repository.
+func SetRepositoryHealthy(name string, healthy bool) {
+	value := 0.0
+	if healthy {
+		value = 1.0
+	}
+	RepositoryHealthy.WithLabelValues(name).Set(value)
+}
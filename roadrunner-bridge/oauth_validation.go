@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	googleJWKSURL        = "https://www.googleapis.com/oauth2/v3/certs"
+	jwksRefreshInterval  = 1 * time.Hour
+	gceMetadataTokenURL  = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/identity"
+	googleIssuerLong     = "https://accounts.google.com"
+	googleIssuerShort    = "accounts.google.com"
+)
+
+// GoogleAuthConfig configures ID token verification for the bridge.
+type GoogleAuthConfig struct {
+	// Audiences lists the client IDs tokens are accepted for.
+	Audiences []string
+	// AllowedHostedDomains restricts G Suite/Workspace tokens to these
+	// "hd" claim values. Empty means any domain (or none) is accepted.
+	AllowedHostedDomains []string
+	// RequiredScopes lists OAuth scopes that must all be present in the
+	// token's "scope" claim, when set.
+	RequiredScopes []string
+}
+
+// Claims holds the verified fields of a Google-issued ID token.
+type Claims struct {
+	Issuer        string
+	Audience      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	HostedDomain  string
+	Scope         string
+	IssuedAt      time.Time
+	ExpiresAt     time.Time
+}
+
+// idTokenClaimsKey is the typed context key verified claims are stored
+// under by authMiddleware.
+type idTokenClaimsKey struct{}
+
+// withIDTokenClaims returns a copy of ctx carrying claims.
+func withIDTokenClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, idTokenClaimsKey{}, claims)
+}
+
+// IDTokenClaimsFromRequest retrieves the Claims verified by authMiddleware,
+// so downstream handlers can authorize by email or hosted domain.
+func IDTokenClaimsFromRequest(r *http.Request) (*Claims, bool) {
+	claims, ok := r.Context().Value(idTokenClaimsKey{}).(*Claims)
+	return claims, ok
+}
+
+// jwksCache caches Google's public JWKS, refreshing it on a fixed interval
+// rather than on every token verification.
+type jwksCache struct {
+	mu        sync.Mutex
+	keys      map[string]*jwksKey
+	fetchedAt time.Time
+	ttl       time.Duration
+	client    *http.Client
+	url       string
+}
+
+type jwksKey struct {
+	kty string
+	n   string
+	e   string
+	alg string
+}
+
+func newJWKSCache(url string, ttl time.Duration) *jwksCache {
+	return &jwksCache{
+		keys:   make(map[string]*jwksKey),
+		ttl:    ttl,
+		client: &http.Client{Timeout: 10 * time.Second},
+		url:    url,
+	}
+}
+
+type jwksResponse struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		Alg string `json:"alg"`
+	} `json:"keys"`
+}
+
+func (c *jwksCache) get(kid string) (*jwksKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.fetchedAt) > c.ttl {
+		if err := c.refreshLocked(); err != nil {
+			if len(c.keys) == 0 {
+				return nil, err
+			}
+			// Serve stale keys rather than failing every request if a
+			// transient refresh fails but we still have a prior key set.
+		}
+	}
+
+	key, exists := c.keys[kid]
+	if !exists {
+		return nil, fmt.Errorf("no JWKS key found for kid %q", kid)
+	}
+	return key, nil
+}
+
+func (c *jwksCache) refreshLocked() error {
+	resp, err := c.client.Get(c.url)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read JWKS response: %w", err)
+	}
+
+	var parsed jwksResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return fmt.Errorf("failed to parse JWKS response: %w", err)
+	}
+
+	keys := make(map[string]*jwksKey, len(parsed.Keys))
+	for _, k := range parsed.Keys {
+		keys[k.Kid] = &jwksKey{kty: k.Kty, n: k.N, e: k.E, alg: k.Alg}
+	}
+
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	return nil
+}
+
+// VerifyIDToken validates raw against Google's public JWKS: signature,
+// issuer, audience, expiry, and not-before. It returns the decoded claims
+// on success.
+func (gam *GoogleAuthManager) VerifyIDToken(ctx context.Context, raw string) (*Claims, error) {
+	if gam.jwks == nil {
+		gam.jwks = newJWKSCache(googleJWKSURL, jwksRefreshInterval)
+	}
+
+	token, err := jwt.Parse(raw, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := gam.jwks.get(kid)
+		if err != nil {
+			return nil, err
+		}
+		return jwkToRSAPublicKey(key)
+	}, jwt.WithValidMethods([]string{"RS256"}))
+	if err != nil {
+		return nil, fmt.Errorf("id token signature/claims validation failed: %w", err)
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("id token is not valid")
+	}
+
+	claims := &Claims{
+		Issuer:        stringClaim(mapClaims, "iss"),
+		Audience:      stringClaim(mapClaims, "aud"),
+		Subject:       stringClaim(mapClaims, "sub"),
+		Email:         stringClaim(mapClaims, "email"),
+		EmailVerified: stringClaim(mapClaims, "email_verified") == "true",
+		HostedDomain:  stringClaim(mapClaims, "hd"),
+		Scope:         stringClaim(mapClaims, "scope"),
+	}
+	if exp, err := mapClaims.GetExpirationTime(); err == nil && exp != nil {
+		claims.ExpiresAt = exp.Time
+	}
+	if iat, err := mapClaims.GetIssuedAt(); err == nil && iat != nil {
+		claims.IssuedAt = iat.Time
+	}
+
+	if claims.Issuer != googleIssuerLong && claims.Issuer != googleIssuerShort {
+		return nil, fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+
+	if len(gam.authConfig.Audiences) > 0 && !containsString(gam.authConfig.Audiences, claims.Audience) {
+		return nil, fmt.Errorf("unexpected audience %q", claims.Audience)
+	}
+
+	if len(gam.authConfig.AllowedHostedDomains) > 0 && !containsString(gam.authConfig.AllowedHostedDomains, claims.HostedDomain) {
+		return nil, fmt.Errorf("hosted domain %q is not allowed", claims.HostedDomain)
+	}
+
+	for _, scope := range gam.authConfig.RequiredScopes {
+		if !strings.Contains(claims.Scope, scope) {
+			return nil, fmt.Errorf("required scope %q missing", scope)
+		}
+	}
+
+	return claims, nil
+}
+
+// identityTokenFromMetadataServer mints an ID token scoped to audience via
+// the GCE metadata server, for service-to-service calls between
+// GCE/GKE-hosted deployments where no JSON key is available.
+func identityTokenFromMetadataServer(ctx context.Context, audience string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s?audience=%s&format=full", gceMetadataTokenURL, audience), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach GCE metadata server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata server returned status %d", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return string(body), nil
+}
+
+func stringClaim(claims jwt.MapClaims, key string) string {
+	v, _ := claims[key].(string)
+	return v
+}
+
+func containsString(list []string, target string) bool {
+	for _, v := range list {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
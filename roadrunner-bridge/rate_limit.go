@@ -0,0 +1,423 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/roadrunner-server/roadrunner/v2/plugins/kv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	rateLimitDecisions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bridge_rate_limit_decisions_total",
+		Help: "Rate limiter allow/deny decisions, labeled by scope and outcome.",
+	}, []string{"scope", "decision"})
+)
+
+// RateLimitRule configures a single token bucket: how fast it refills
+// (Rps), how many requests it can absorb in a burst (Burst), and which
+// part of the request the bucket key is derived from (Scope).
+type RateLimitRule struct {
+	Rps   float64 `json:"rps"`
+	Burst int     `json:"burst"`
+	// Scope is one of "repository", "ip", "principal", or "tenant".
+	Scope string `json:"scope"`
+}
+
+// bucketState is the refillable token-bucket state tracked per composite
+// key, whether held in memory or serialized into the KV backend. Version
+// is bumped on every write so kvBucketStore's CAS retry loop can detect a
+// concurrent writer that raced it between load and save.
+type bucketState struct {
+	Tokens     float64   `json:"tokens"`
+	LastRefill time.Time `json:"last_refill"`
+	Version    int       `json:"version"`
+}
+
+// bucketStore abstracts where token-bucket state lives, so RateLimiter can
+// fall back to an in-memory store when the KV backend is unavailable.
+type bucketStore interface {
+	// take attempts to remove one token from the bucket identified by key,
+	// creating it with the rule's burst capacity if it doesn't exist yet.
+	// It returns whether the request is allowed and the tokens remaining.
+	take(ctx context.Context, key string, rule RateLimitRule) (allowed bool, remaining float64, err error)
+}
+
+// memoryBucketStore is an in-process token-bucket store, used when the KV
+// plugin is unavailable (or for single-instance deployments).
+type memoryBucketStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucketState
+}
+
+func newMemoryBucketStore() *memoryBucketStore {
+	return &memoryBucketStore{buckets: make(map[string]*bucketState)}
+}
+
+func (s *memoryBucketStore) take(ctx context.Context, key string, rule RateLimitRule) (bool, float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	state, exists := s.buckets[key]
+	if !exists {
+		state = &bucketState{Tokens: float64(rule.Burst), LastRefill: now}
+		s.buckets[key] = state
+	}
+
+	refill(state, rule, now)
+
+	if state.Tokens < 1 {
+		return false, state.Tokens, nil
+	}
+
+	state.Tokens--
+	return true, state.Tokens, nil
+}
+
+// kvBucketStoreMaxCASAttempts bounds the version-checked retry loop in
+// kvBucketStore.take so a hot key under contention returns an error rather
+// than retrying forever. The request is not denied on that account:
+// RateLimiter.Allow then decides it from its in-memory fallback bucket.
+const kvBucketStoreMaxCASAttempts = 5
+
+// rateLimitLuaScript performs the entire refill-and-decrement round trip
+// as a single atomic operation on the Redis server, so two replicas
+// racing on the same key can never both observe tokens >= 1 and both
+// decrement: KEYS[1] is the bucket key, ARGV is burst, rps, now (unix
+// seconds), ttl (seconds). It returns {allowed, tokens_remaining}.
+const rateLimitLuaScript = `
+local burst = tonumber(ARGV[1])
+local rps = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+
+local tokens = burst
+local last_refill = now
+local raw = redis.call("GET", KEYS[1])
+if raw then
+	local state = cjson.decode(raw)
+	tokens = state.tokens
+	last_refill = state.last_refill
+end
+
+local elapsed = now - last_refill
+tokens = math.min(burst, tokens + elapsed * rps)
+
+local allowed = 0
+if tokens >= 1 then
+	allowed = 1
+	tokens = tokens - 1
+end
+
+redis.call("SET", KEYS[1], cjson.encode({tokens = tokens, last_refill = now}), "EX", ttl)
+return {allowed, tostring(tokens)}
+`
+
+// kvBucketStore persists bucket state in the RoadRunner KV plugin so rate
+// limits survive restarts and are shared across replicas. When redis is
+// non-nil, take runs rateLimitLuaScript, which performs the entire
+// refill-and-decrement round trip as one atomic operation on the Redis
+// server — this is the only path that is actually race-free under
+// concurrent load. Without a redis.Client, take falls back to
+// takeViaCAS, which re-checks a version stamp immediately before writing
+// and retries (bounded by kvBucketStoreMaxCASAttempts) if a concurrent
+// writer advanced it in between. The RoadRunner kv.Plugin interface has
+// no conditional/compare-and-swap Set, so that re-check and the eventual
+// Set are still two separate round trips: it narrows the race window but
+// does not close it, and two requests can still rarely both win and
+// over-grant a token. Deployments that need a hard guarantee under
+// concurrent load should wire a redis.Client.
+type kvBucketStore struct {
+	kv     *kv.Plugin
+	redis  *redis.Client
+	prefix string
+}
+
+func newKVBucketStore(kvPlugin *kv.Plugin, redisClient *redis.Client, prefix string) *kvBucketStore {
+	return &kvBucketStore{kv: kvPlugin, redis: redisClient, prefix: prefix}
+}
+
+func (s *kvBucketStore) take(ctx context.Context, key string, rule RateLimitRule) (bool, float64, error) {
+	storageKey := s.prefix + key
+
+	if s.redis != nil {
+		return s.takeViaScript(ctx, storageKey, rule)
+	}
+	return s.takeViaCAS(ctx, storageKey, rule)
+}
+
+func (s *kvBucketStore) takeViaScript(ctx context.Context, storageKey string, rule RateLimitRule) (bool, float64, error) {
+	ttl := time.Duration(float64(rule.Burst)/maxFloat(rule.Rps, 0.01)) * time.Second
+
+	res, err := s.redis.Eval(ctx, rateLimitLuaScript, []string{storageKey},
+		rule.Burst, rule.Rps, time.Now().Unix(), int64(ttl.Seconds())).Result()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to evaluate rate limit script for %s: %w", storageKey, err)
+	}
+
+	fields, ok := res.([]interface{})
+	if !ok || len(fields) != 2 {
+		return false, 0, fmt.Errorf("unexpected rate limit script result for %s: %v", storageKey, res)
+	}
+	allowed := fields[0] == int64(1)
+	remaining, err := strconv.ParseFloat(fmt.Sprint(fields[1]), 64)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to parse rate limit script result for %s: %w", storageKey, err)
+	}
+
+	return allowed, remaining, nil
+}
+
+// takeViaCAS is the best-effort fallback used when no redis.Client is
+// wired in. kv.Plugin's Set has no compare-and-swap primitive, so the
+// version re-check below and the eventual Set are two separate round
+// trips: two goroutines can still both pass the check and both
+// unconditionally overwrite, one clobbering the other's decrement. This
+// narrows the race window (retrying instead of blindly overwriting on
+// every call) but is not a correctness guarantee; it is not safe under
+// sustained concurrent load from multiple replicas. Use a redis.Client
+// for that (see takeViaScript).
+func (s *kvBucketStore) takeViaCAS(ctx context.Context, storageKey string, rule RateLimitRule) (bool, float64, error) {
+	ttl := time.Duration(float64(rule.Burst)/maxFloat(rule.Rps, 0.01)) * time.Second
+
+	for attempt := 0; attempt < kvBucketStoreMaxCASAttempts; attempt++ {
+		state, err := s.loadState(storageKey)
+		if err != nil {
+			return false, 0, fmt.Errorf("failed to load rate limit bucket %s: %w", storageKey, err)
+		}
+
+		now := time.Now()
+		if state == nil {
+			state = &bucketState{Tokens: float64(rule.Burst), LastRefill: now}
+		}
+		refill(state, rule, now)
+
+		allowed := state.Tokens >= 1
+		if allowed {
+			state.Tokens--
+		}
+
+		// Re-read immediately before writing: if another request already
+		// advanced the version, our decision above was made against stale
+		// data, so discard it and retry. This shrinks the window but
+		// doesn't close it — the check and the Set below are still two
+		// unsynchronized round trips against a backend with no CAS.
+		current, err := s.loadState(storageKey)
+		if err != nil {
+			return false, 0, fmt.Errorf("failed to verify rate limit bucket %s: %w", storageKey, err)
+		}
+		currentVersion := 0
+		if current != nil {
+			currentVersion = current.Version
+		}
+		if currentVersion != state.Version {
+			continue
+		}
+
+		state.Version++
+		if err := s.saveState(storageKey, state, ttl); err != nil {
+			return false, 0, fmt.Errorf("failed to persist rate limit bucket %s: %w", storageKey, err)
+		}
+
+		return allowed, state.Tokens, nil
+	}
+
+	// Couldn't win the CAS race within the retry budget; report the
+	// contention without recording a decrement. RateLimiter.Allow answers
+	// this error from its in-memory fallback bucket.
+	return false, 0, fmt.Errorf("rate limit bucket %s contended after %d attempts", storageKey, kvBucketStoreMaxCASAttempts)
+}
+
+func (s *kvBucketStore) loadState(storageKey string) (*bucketState, error) {
+	items, err := s.kv.MGet(storageKey)
+	if err != nil {
+		return nil, err
+	}
+	raw, exists := items[storageKey]
+	if !exists || raw == nil {
+		return nil, nil
+	}
+
+	serialized, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected value type %T for rate limit bucket %s", raw, storageKey)
+	}
+
+	return decodeBucketState([]byte(serialized))
+}
+
+func (s *kvBucketStore) saveState(storageKey string, state *bucketState, ttl time.Duration) error {
+	raw, err := encodeBucketState(state)
+	if err != nil {
+		return err
+	}
+
+	return s.kv.Set(&kv.Item{
+		Key:   storageKey,
+		Value: string(raw),
+		TTL:   fmt.Sprintf("%d", int64(ttl.Seconds())),
+	})
+}
+
+// refill adds tokens accrued since state.LastRefill at rule.Rps tokens per
+// second, capped at rule.Burst.
+func refill(state *bucketState, rule RateLimitRule, now time.Time) {
+	elapsed := now.Sub(state.LastRefill).Seconds()
+	state.Tokens += elapsed * rule.Rps
+	if state.Tokens > float64(rule.Burst) {
+		state.Tokens = float64(rule.Burst)
+	}
+	state.LastRefill = now
+}
+
+func encodeBucketState(state *bucketState) ([]byte, error) {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode rate limit bucket state: %w", err)
+	}
+	return raw, nil
+}
+
+func decodeBucketState(raw []byte) (*bucketState, error) {
+	var state bucketState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode rate limit bucket state: %w", err)
+	}
+	return &state, nil
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// RateLimiter enforces per-key token buckets, composing the bucket key
+// from the rule's configured scope (repository, ip, principal, or tenant).
+type RateLimiter struct {
+	rules    map[string]RateLimitRule
+	store    bucketStore
+	fallback bucketStore
+}
+
+// NewRateLimiter builds a RateLimiter backed by the KV plugin when
+// available, with an in-memory fallback for when it isn't. redisClient is
+// optional; when set, kvBucketStore uses it to run the atomic Lua
+// fast path instead of the version-checked CAS retry loop.
+func NewRateLimiter(rules map[string]RateLimitRule, kvPlugin *kv.Plugin, redisClient *redis.Client) *RateLimiter {
+	rl := &RateLimiter{
+		rules:    rules,
+		fallback: newMemoryBucketStore(),
+	}
+	if kvPlugin != nil {
+		rl.store = newKVBucketStore(kvPlugin, redisClient, "bridge:ratelimit:")
+	} else {
+		rl.store = rl.fallback
+	}
+	return rl
+}
+
+// Allow checks the token bucket for (repository, principal, remoteAddr),
+// using whichever rule is configured for the repository (or
+// "default" otherwise). It returns the composite key used (for response
+// headers/logging), whether the request is allowed, and tokens remaining.
+func (rl *RateLimiter) Allow(ctx context.Context, repository, principal, remoteAddr string) (string, bool, float64, error) {
+	rule, exists := rl.rules[repository]
+	if !exists {
+		rule, exists = rl.rules["default"]
+		if !exists {
+			rule = RateLimitRule{Rps: 10, Burst: 20, Scope: "repository"}
+		}
+	}
+
+	key := compositeRateLimitKey(rule.Scope, repository, principal, remoteAddr)
+
+	allowed, remaining, err := rl.store.take(ctx, key, rule)
+	if err != nil {
+		// KV backend unavailable; fall back to the in-memory store rather
+		// than fail open or closed on every request.
+		allowed, remaining, err = rl.fallback.take(ctx, key, rule)
+	}
+
+	decision := "deny"
+	if allowed {
+		decision = "allow"
+	}
+	rateLimitDecisions.WithLabelValues(rule.Scope, decision).Inc()
+
+	return key, allowed, remaining, err
+}
+
+func compositeRateLimitKey(scope, repository, principal, remoteAddr string) string {
+	switch scope {
+	case "principal":
+		return fmt.Sprintf("repo:%s|principal:%s", repository, principal)
+	case "ip":
+		return fmt.Sprintf("repo:%s|ip:%s", repository, remoteAddr)
+	case "tenant":
+		tenant := principal
+		if idx := strings.Index(principal, "@"); idx != -1 {
+			tenant = principal[idx+1:]
+		}
+		return fmt.Sprintf("repo:%s|tenant:%s", repository, tenant)
+	default:
+		return fmt.Sprintf("repo:%s", repository)
+	}
+}
+
+// extractRepositoryFromPath pulls the {repository} path variable out of
+// /bridge/{repository}/{action}, mirroring extractActionFromPath.
+func extractRepositoryFromPath(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) >= 2 && parts[0] == "bridge" {
+		return parts[1]
+	}
+	return ""
+}
+
+// rateLimitMiddleware enforces the configured RateLimiter, composing a key
+// from the request's repository, authenticated principal, and remote
+// address, and emitting X-RateLimit-Remaining/Retry-After headers.
+func (bg *BridgeGateway) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if bg.rateLimiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		repository := extractRepositoryFromPath(r.URL.Path)
+		principal := ""
+		if claims, ok := IDTokenClaimsFromRequest(r); ok {
+			principal = claims.Email
+		}
+
+		_, allowed, remaining, err := bg.rateLimiter.Allow(r.Context(), repository, principal, r.RemoteAddr)
+		if err != nil {
+			// Fail open: don't block traffic because the limiter errored.
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(int(remaining)))
+
+		if !allowed {
+			w.Header().Set("Retry-After", "1")
+			bg.sendErrorResponse(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// circuitState is the state of a RepositoryAdapter's circuit breaker.
+type circuitState string
+
+const (
+	circuitClosed   circuitState = "closed"
+	circuitOpen     circuitState = "open"
+	circuitHalfOpen circuitState = "half_open"
+)
+
+const (
+	circuitBreakerErrorThreshold = 3
+	circuitBreakerCoolDown       = 30 * time.Second
+)
+
+// circuitBreaker tracks consecutive failures for a single adapter and
+// decides whether it's eligible to serve traffic. A primary that trips the
+// breaker is ejected for circuitBreakerCoolDown; after that the breaker is
+// half-open and lets requests through until one succeeds (closing it) or
+// fails (opening it again).
+type circuitBreaker struct {
+	mu                  sync.Mutex
+	state               circuitState
+	consecutiveFailures int
+	openedAt            time.Time
+}
+
+func newCircuitBreaker() *circuitBreaker {
+	return &circuitBreaker{state: circuitClosed}
+}
+
+// allow reports whether a request may be dispatched to this adapter right
+// now, transitioning open -> half-open once the cool-down has elapsed.
+func (cb *circuitBreaker) allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case circuitOpen:
+		if time.Since(cb.openedAt) >= circuitBreakerCoolDown {
+			cb.state = circuitHalfOpen
+			return true
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+func (cb *circuitBreaker) recordSuccess() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.consecutiveFailures = 0
+	cb.state = circuitClosed
+}
+
+func (cb *circuitBreaker) recordFailure() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.consecutiveFailures++
+
+	if cb.state == circuitHalfOpen || cb.consecutiveFailures >= circuitBreakerErrorThreshold {
+		cb.state = circuitOpen
+		cb.openedAt = time.Now()
+	}
+}
+
+func (cb *circuitBreaker) snapshot() (circuitState, int) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.state, cb.consecutiveFailures
+}
+
+// routingGroup holds the adapters sharing a RepoConfig.Group, ordered by
+// priority (lowest value first), along with each adapter's circuit breaker.
+type routingGroup struct {
+	mu       sync.Mutex
+	members  []string // adapter names, ordered by priority
+	breakers map[string]*circuitBreaker
+}
+
+// buildRoutingGroups groups repositories by RepoConfig.Group (defaulting a
+// group to the repository's own name when unset, so ungrouped repos still
+// route through a single-member group), ordering each group's members by
+// ascending Priority.
+func buildRoutingGroups(repositories map[string]*RepositoryAdapter) map[string]*routingGroup {
+	groups := make(map[string]*routingGroup)
+
+	for name, adapter := range repositories {
+		group := adapter.Config.Group
+		if group == "" {
+			group = name
+		}
+
+		rg, exists := groups[group]
+		if !exists {
+			rg = &routingGroup{breakers: make(map[string]*circuitBreaker)}
+			groups[group] = rg
+		}
+		rg.members = append(rg.members, name)
+		rg.breakers[name] = newCircuitBreaker()
+	}
+
+	for _, rg := range groups {
+		members := rg.members
+		sort.SliceStable(members, func(i, j int) bool {
+			return repositories[members[i]].Config.Priority < repositories[members[j]].Config.Priority
+		})
+	}
+
+	return groups
+}
+
+// primary returns the highest-priority member currently eligible to serve
+// traffic (healthy and with a closed/half-open circuit), falling back to
+// the top-priority member if every peer is unavailable.
+func (rg *routingGroup) primary(repositories map[string]*RepositoryAdapter) string {
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	for _, name := range rg.members {
+		adapter, exists := repositories[name]
+		if !exists || !adapter.IsHealthy {
+			continue
+		}
+		if !rg.breakers[name].allow() {
+			continue
+		}
+		return name
+	}
+
+	if len(rg.members) > 0 {
+		return rg.members[0]
+	}
+	return ""
+}
+
+// Route dispatches req to the currently elected primary for group, failing
+// over to the next-priority peer when the primary's circuit is open or the
+// call itself fails. It returns an error only once every member has been
+// exhausted.
+func (rm *RepositoryManager) Route(ctx context.Context, group string, req BridgeRequest) (*BridgeResponse, string, error) {
+	rm.mu.RLock()
+	rg, exists := rm.groups[group]
+	rm.mu.RUnlock()
+	if !exists {
+		return nil, "", fmt.Errorf("no repository group named %q", group)
+	}
+
+	rg.mu.Lock()
+	members := append([]string(nil), rg.members...)
+	rg.mu.Unlock()
+
+	var lastErr error
+	for _, name := range members {
+		adapter, exists := rm.repositories[name]
+		if !exists || !adapter.IsHealthy {
+			lastErr = fmt.Errorf("repository %q is unhealthy or missing", name)
+			continue
+		}
+
+		breaker := rg.breakers[name]
+		if !breaker.allow() {
+			lastErr = fmt.Errorf("repository %q circuit breaker is open", name)
+			continue
+		}
+
+		resp, err := adapter.Transport.Invoke(ctx, req)
+		if err != nil {
+			breaker.recordFailure()
+			lastErr = err
+			continue
+		}
+
+		breaker.recordSuccess()
+		return resp, name, nil
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no members available in repository group %q", group)
+	}
+	return nil, "", lastErr
+}
+
+// GroupStatus describes a routing group's circuit breaker state, for the
+// /repositories endpoint.
+type GroupStatus struct {
+	Primary string         `json:"primary"`
+	Members []MemberStatus `json:"members"`
+}
+
+// MemberStatus describes a single adapter's circuit breaker state.
+type MemberStatus struct {
+	Name                string `json:"name"`
+	Priority            int    `json:"priority"`
+	Healthy             bool   `json:"healthy"`
+	CircuitState        string `json:"circuit_state"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+}
+
+// GroupStatuses returns the circuit state, consecutive failure count, and
+// elected primary for every routing group.
+func (rm *RepositoryManager) GroupStatuses() map[string]GroupStatus {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	statuses := make(map[string]GroupStatus, len(rm.groups))
+	for group, rg := range rm.groups {
+		rg.mu.Lock()
+		members := append([]string(nil), rg.members...)
+		rg.mu.Unlock()
+
+		memberStatuses := make([]MemberStatus, 0, len(members))
+		for _, name := range members {
+			adapter := rm.repositories[name]
+			state, failures := rg.breakers[name].snapshot()
+			memberStatuses = append(memberStatuses, MemberStatus{
+				Name:                name,
+				Priority:            adapter.Config.Priority,
+				Healthy:             adapter.IsHealthy,
+				CircuitState:        string(state),
+				ConsecutiveFailures: failures,
+			})
+		}
+
+		statuses[group] = GroupStatus{
+			Primary: rg.primary(rm.repositories),
+			Members: memberStatuses,
+		}
+	}
+
+	return statuses
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldRule validates a single field of BridgeRequest.Data using a
+// validator/v10 tag (e.g. "required", "required,email").
+type FieldRule struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message,omitempty"`
+}
+
+// ActionSchema is the validation contract for one {repository, action}
+// pair, loaded from SchemaRegistry's directory and also served directly by
+// GET /bridge/{repository}/{action}/schema.
+type ActionSchema struct {
+	Repository string      `json:"repository"`
+	Action     string      `json:"action"`
+	Fields     []FieldRule `json:"fields"`
+}
+
+// ValidationError describes a single field that failed validation.
+type ValidationError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// SchemaRegistry loads per-{repository,action} validation schemas from a
+// directory (one JSON file per action, at {dir}/{repository}/{action}.json)
+// and validates BridgeRequest.Data against them. Reload is safe to call
+// concurrently with Validate/Lookup, so it can be wired to SIGHUP.
+type SchemaRegistry struct {
+	mu       sync.RWMutex
+	dir      string
+	validate *validator.Validate
+	schemas  map[string]*ActionSchema
+}
+
+// NewSchemaRegistry builds a registry and performs an initial load. A
+// missing directory is not an error: it just means no schemas are
+// enforced until one is created and the registry is reloaded.
+func NewSchemaRegistry(dir string) (*SchemaRegistry, error) {
+	sr := &SchemaRegistry{
+		dir:      dir,
+		validate: validator.New(),
+		schemas:  make(map[string]*ActionSchema),
+	}
+
+	if err := sr.Reload(); err != nil {
+		return nil, err
+	}
+	return sr, nil
+}
+
+func schemaKey(repository, action string) string {
+	return repository + "/" + action
+}
+
+// Reload re-walks the schema directory and replaces the registry's schema
+// set atomically, so in-flight Lookup/Validate calls never see a partial
+// load.
+func (sr *SchemaRegistry) Reload() error {
+	if _, err := os.Stat(sr.dir); os.IsNotExist(err) {
+		sr.mu.Lock()
+		sr.schemas = make(map[string]*ActionSchema)
+		sr.mu.Unlock()
+		return nil
+	}
+
+	schemas := make(map[string]*ActionSchema)
+
+	err := filepath.Walk(sr.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read schema file %s: %w", path, err)
+		}
+
+		var schema ActionSchema
+		if err := json.Unmarshal(raw, &schema); err != nil {
+			return fmt.Errorf("failed to parse schema file %s: %w", path, err)
+		}
+
+		if schema.Repository == "" || schema.Action == "" {
+			rel, _ := filepath.Rel(sr.dir, path)
+			parts := strings.Split(filepath.ToSlash(rel), "/")
+			if len(parts) == 2 {
+				schema.Repository = parts[0]
+				schema.Action = strings.TrimSuffix(parts[1], ".json")
+			}
+		}
+
+		schemas[schemaKey(schema.Repository, schema.Action)] = &schema
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load schema registry from %s: %w", sr.dir, err)
+	}
+
+	sr.mu.Lock()
+	sr.schemas = schemas
+	sr.mu.Unlock()
+	return nil
+}
+
+// Lookup returns the schema registered for {repository, action}, if any.
+func (sr *SchemaRegistry) Lookup(repository, action string) (*ActionSchema, bool) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+	schema, exists := sr.schemas[schemaKey(repository, action)]
+	return schema, exists
+}
+
+// Validate checks data against the schema registered for {repository,
+// action}. It returns nil (no errors, and no schema registered is treated
+// the same as a pass) when there's nothing to enforce.
+func (sr *SchemaRegistry) Validate(repository, action string, data map[string]interface{}) []ValidationError {
+	schema, exists := sr.Lookup(repository, action)
+	if !exists {
+		return nil
+	}
+
+	var errs []ValidationError
+	for _, field := range schema.Fields {
+		if err := sr.validate.Var(data[field.Field], field.Rule); err != nil {
+			message := field.Message
+			if message == "" {
+				message = fmt.Sprintf("field %q failed validation rule %q", field.Field, field.Rule)
+			}
+			errs = append(errs, ValidationError{Field: field.Field, Rule: field.Rule, Message: message})
+		}
+	}
+	return errs
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerServiceName = "roadrunner-bridge"
+
+// initTracerProvider builds an OTLP/HTTP-exporting TracerProvider for the
+// bridge and registers it, along with the W3C trace-context propagator, as
+// the global OpenTelemetry provider so otelmux/otelhttp pick it up.
+func initTracerProvider(ctx context.Context, endpoint string) (*sdktrace.TracerProvider, error) {
+	if endpoint == "" {
+		endpoint = "localhost:4318"
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpoint(endpoint), otlptracehttp.WithInsecure())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx, resource.WithAttributes(
+		semconv.ServiceName(tracerServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build otel resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return tp, nil
+}
+
+// tracingMiddleware annotates the span that otelmux extracted from the
+// incoming W3C traceparent/tracestate headers with bridge-specific
+// attributes, so repository/action show up without digging through logs.
+func (bg *BridgeGateway) tracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		span := trace.SpanFromContext(r.Context())
+
+		vars := mux.Vars(r)
+		if repository := vars["repository"]; repository != "" {
+			span.SetAttributes(attribute.String("bridge.repository", repository))
+		}
+		if action := vars["action"]; action != "" {
+			span.SetAttributes(attribute.String("bridge.action", action))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// traceMetadata returns the trace_id/span_id of the span active in ctx,
+// for injection into BridgeRequest.Metadata so upstream services can
+// continue the trace even over transports (jobs, grpc) that don't carry
+// HTTP headers.
+func traceMetadata(ctx context.Context) map[string]string {
+	sc := trace.SpanFromContext(ctx).SpanContext()
+	if !sc.IsValid() {
+		return nil
+	}
+	return map[string]string{
+		"trace_id": sc.TraceID().String(),
+		"span_id":  sc.SpanID().String(),
+	}
+}
+
+// recordUpstreamSpan sets upstream-call attributes/status on the span
+// active in ctx, marking it errored on a transport failure or a 5xx.
+func recordUpstreamSpan(ctx context.Context, url string, statusCode int, err error) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(
+		attribute.String("bridge.upstream_url", url),
+		attribute.Int("bridge.upstream_status", statusCode),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	if statusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, fmt.Sprintf("upstream returned status %d", statusCode))
+	}
+}
+
+// otelMuxMiddleware wraps otelmux.Middleware so it can be registered the
+// same way as the gateway's own mux.MiddlewareFunc-typed middlewares.
+func otelMuxMiddleware() mux.MiddlewareFunc {
+	return otelmux.Middleware(tracerServiceName)
+}
+
+// tracedHTTPTransport wraps rt with otelhttp so outgoing calls create a
+// child span of the request's span and propagate W3C trace-context
+// headers to the upstream.
+func tracedHTTPTransport(rt http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(rt)
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/roadrunner-server/roadrunner/v2/plugins/jobs"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// RepositoryTransport abstracts how a BridgeRequest reaches a specific
+// upstream repository. RepoConfig.Transport (falling back to
+// RepoConfig.Type) selects the implementation NewRepositoryManager wires up
+// for each adapter.
+type RepositoryTransport interface {
+	Invoke(ctx context.Context, req BridgeRequest) (*BridgeResponse, error)
+	// InvokeStream returns the raw upstream response body so SSE/chunked
+	// responses can be proxied without buffering.
+	InvokeStream(ctx context.Context, req BridgeRequest) (io.ReadCloser, error)
+	HealthCheck(ctx context.Context) error
+	Close() error
+}
+
+// httpTransport is the original behavior: a plain JSON-over-HTTP call to
+// RepoConfig.URL.
+type httpTransport struct {
+	config RepoConfig
+	client *http.Client
+}
+
+func newHTTPTransport(config RepoConfig) *httpTransport {
+	return &httpTransport{
+		config: config,
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: tracedHTTPTransport(http.DefaultTransport),
+		},
+	}
+}
+
+func (t *httpTransport) Invoke(ctx context.Context, req BridgeRequest) (*BridgeResponse, error) {
+	body, err := json.Marshal(req.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request data: %w", err)
+	}
+
+	url := t.config.URL + "/" + req.Action
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(httpReq)
+	if err != nil {
+		recordUpstreamSpan(ctx, url, 0, err)
+		return nil, fmt.Errorf("http transport call to %s failed: %w", url, err)
+	}
+	defer resp.Body.Close()
+	recordUpstreamSpan(ctx, url, resp.StatusCode, nil)
+
+	var data map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		data = make(map[string]interface{})
+	}
+
+	return &BridgeResponse{
+		Success: resp.StatusCode < http.StatusBadRequest,
+		Data:    data,
+	}, nil
+}
+
+func (t *httpTransport) InvokeStream(ctx context.Context, req BridgeRequest) (io.ReadCloser, error) {
+	body, err := json.Marshal(req.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request data: %w", err)
+	}
+
+	url := t.config.URL + "/" + req.Action
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	resp, err := t.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("http transport stream call to %s failed: %w", url, err)
+	}
+
+	return resp.Body, nil
+}
+
+func (t *httpTransport) HealthCheck(ctx context.Context) error {
+	if t.config.HealthCheck == "" {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.config.URL+t.config.HealthCheck, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (t *httpTransport) Close() error { return nil }
+
+// grpcTransport invokes an upstream via gRPC, marshaling BridgeRequest.Data
+// to a google.protobuf.Struct via protojson so repositories don't need a
+// bridge-specific .proto contract.
+type grpcTransport struct {
+	config RepoConfig
+	conn   *grpc.ClientConn
+}
+
+func newGRPCTransport(config RepoConfig) (*grpcTransport, error) {
+	conn, err := grpc.Dial(config.URL, grpc.WithInsecure())
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial grpc target %s: %w", config.URL, err)
+	}
+
+	return &grpcTransport{config: config, conn: conn}, nil
+}
+
+func (t *grpcTransport) Invoke(ctx context.Context, req BridgeRequest) (*BridgeResponse, error) {
+	payload, err := structpb.NewStruct(req.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert request data to protobuf struct: %w", err)
+	}
+
+	reply := &structpb.Struct{}
+	method := fmt.Sprintf("/bridge.RepositoryService/%s", req.Action)
+	if err := t.conn.Invoke(ctx, method, payload, reply); err != nil {
+		return nil, fmt.Errorf("grpc transport call %s failed: %w", method, err)
+	}
+
+	data, err := protojsonStructToMap(reply)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BridgeResponse{Success: true, Data: data}, nil
+}
+
+func (t *grpcTransport) InvokeStream(ctx context.Context, req BridgeRequest) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("grpc transport does not support InvokeStream for %s", req.Repository)
+}
+
+func (t *grpcTransport) HealthCheck(ctx context.Context) error {
+	state := t.conn.GetState()
+	if state.String() == "SHUTDOWN" {
+		return fmt.Errorf("grpc connection to %s is shut down", t.config.URL)
+	}
+	return nil
+}
+
+func (t *grpcTransport) Close() error {
+	return t.conn.Close()
+}
+
+func protojsonStructToMap(s *structpb.Struct) (map[string]interface{}, error) {
+	raw, err := protojson.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal grpc response: %w", err)
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode grpc response: %w", err)
+	}
+	return data, nil
+}
+
+// jobsTransport pushes a BridgeRequest onto a RoadRunner job queue instead
+// of calling the repository synchronously, for actions that are processed
+// asynchronously by a worker pool.
+type jobsTransport struct {
+	config     RepoConfig
+	jobsPlugin *jobs.Plugin
+}
+
+func newJobsTransport(config RepoConfig, jobsPlugin *jobs.Plugin) *jobsTransport {
+	return &jobsTransport{config: config, jobsPlugin: jobsPlugin}
+}
+
+func (t *jobsTransport) Invoke(ctx context.Context, req BridgeRequest) (*BridgeResponse, error) {
+	jobID := fmt.Sprintf("%s-%s-%d", req.Repository, req.Action, time.Now().UnixNano())
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job payload: %w", err)
+	}
+
+	if err := t.jobsPlugin.Push(ctx, t.config.Name, jobID, payload); err != nil {
+		return nil, fmt.Errorf("failed to push job to queue %s: %w", t.config.Name, err)
+	}
+
+	return &BridgeResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"job_id": jobID,
+			"queue":  t.config.Name,
+			"status": "queued",
+		},
+	}, nil
+}
+
+func (t *jobsTransport) InvokeStream(ctx context.Context, req BridgeRequest) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("jobs transport does not support InvokeStream for %s", req.Repository)
+}
+
+func (t *jobsTransport) HealthCheck(ctx context.Context) error {
+	if t.jobsPlugin == nil {
+		return fmt.Errorf("jobs plugin not initialized")
+	}
+	return nil
+}
+
+func (t *jobsTransport) Close() error { return nil }
+
+// newTransportForRepo selects a RepositoryTransport based on
+// RepoConfig.Transport, falling back to RepoConfig.Type, defaulting to
+// HTTP when neither names a known transport.
+func newTransportForRepo(config RepoConfig, jobsPlugin *jobs.Plugin) (RepositoryTransport, error) {
+	kind := config.Transport
+	if kind == "" {
+		kind = config.Type
+	}
+
+	switch kind {
+	case "grpc":
+		return newGRPCTransport(config)
+	case "jobs", "roadrunner-jobs":
+		return newJobsTransport(config, jobsPlugin), nil
+	default:
+		return newHTTPTransport(config), nil
+	}
+}